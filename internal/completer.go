@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/openGemini/go-prompt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// topLevelSuggestions enumerates the commands recognized by CommandLine.command.
+var topLevelSuggestions = []prompt.Suggest{
+	{Text: "show", Description: "show databases, series, measurements, ..."},
+	{Text: "drop", Description: "drop a database, series, user, ..."},
+	{Text: "create", Description: "create a database, retention policy, user, ..."},
+	{Text: "insert", Description: "write a line protocol point"},
+	{Text: "use", Description: "set current database"},
+	{Text: "auth", Description: "prompt for username and password"},
+	{Text: "precision", Description: "set the timestamp precision"},
+	{Text: "rp", Description: "set current retention policy"},
+	{Text: "grant", Description: "grant a privilege to a user"},
+	{Text: "alter", Description: "alter a retention policy"},
+	{Text: "revoke", Description: "revoke a privilege from a user"},
+	{Text: "kill", Description: "kill a running query"},
+	{Text: "explain", Description: "explain a query plan"},
+	{Text: "set", Description: "set a client option, e.g. ssl, chunked"},
+	{Text: "timeout", Description: "set the per-query timeout"},
+	{Text: "format", Description: "set the output format"},
+	{Text: "help", Description: "show usage"},
+	{Text: "exit", Description: "quit the openGemini shell"},
+}
+
+// showSuggestions enumerates the second-level tokens accepted after "show".
+var showSuggestions = []prompt.Suggest{
+	{Text: "databases"},
+	{Text: "series"},
+	{Text: "measurements"},
+	{Text: "tag keys"},
+	{Text: "field keys"},
+	{Text: "retention policies"},
+	{Text: "users"},
+	{Text: "shards"},
+}
+
+// completionLookupTimeout bounds how long a single "show databases"/"show
+// retention policies" lookup behind the completer may block. It intentionally
+// stays well under stdHttpClient's own retry-and-backoff budget: the REPL's
+// input loop stalls for the duration of the lookup, so a slow or unreachable
+// server must not be allowed to freeze typing for 15+ seconds.
+const completionLookupTimeout = 2 * time.Second
+
+// defaultSuggestionTTL is how long fetched database/retention-policy names
+// are cached before the completer refreshes them.
+const defaultSuggestionTTL = 30 * time.Second
+
+// querier is the subset of HttpClient used by the completer, so tests can
+// inject a fake without standing up a real server.
+type querier func(ctx context.Context, q *QueryValue) ([]byte, error)
+
+// CompleterOptions configures a Completer. The zero value queries
+// stdHttpClient with a 30s suggestion cache TTL and an empty database.
+type CompleterOptions struct {
+	Querier  querier
+	Database func() string
+	TTL      time.Duration
+}
+
+// Completer drives prompt completion for the openGemini command grammar. It
+// statically completes top-level and "show" keywords, and lazily fetches
+// database/retention-policy names once the user has typed "use " or "rp ".
+type Completer struct {
+	opts      CompleterOptions
+	databases cachedSuggestions
+	retention cachedSuggestions
+}
+
+// cachedSuggestions memoizes a suggestion fetch for TTL, refreshing on demand.
+type cachedSuggestions struct {
+	mu      sync.Mutex
+	values  []string
+	fetched time.Time
+}
+
+func (c *cachedSuggestions) get(ttl time.Duration, fetch func() []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values != nil && time.Since(c.fetched) < ttl {
+		return c.values
+	}
+	c.values = fetch()
+	c.fetched = time.Now()
+	return c.values
+}
+
+func NewCompleter() *Completer {
+	return NewCompleterWithOptions(CompleterOptions{})
+}
+
+// NewCompleterWithOptions builds a Completer against opts, filling in
+// defaults for any zero-valued field: Querier calls stdHttpClient.queryOnce,
+// Database reports an empty (unscoped) database, and TTL is 30s.
+func NewCompleterWithOptions(opts CompleterOptions) *Completer {
+	if opts.Querier == nil {
+		opts.Querier = func(ctx context.Context, q *QueryValue) ([]byte, error) {
+			return stdHttpClient.queryOnce(ctx, q)
+		}
+	}
+	if opts.Database == nil {
+		opts.Database = func() string { return "" }
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultSuggestionTTL
+	}
+	return &Completer{opts: opts}
+}
+
+func (c *Completer) completer(d prompt.Document) []prompt.Suggest {
+	word := d.GetWordBeforeCursor()
+	fields := strings.Fields(d.TextBeforeCursor())
+
+	if len(fields) == 0 {
+		return prompt.FilterHasPrefix(topLevelSuggestions, word, true)
+	}
+
+	// Still completing the first token.
+	if len(fields) == 1 && word != "" {
+		return prompt.FilterHasPrefix(topLevelSuggestions, word, true)
+	}
+
+	switch Command(strings.ToLower(fields[0])) {
+	case Show:
+		if len(fields) == 1 || (len(fields) == 2 && word != "") {
+			return prompt.FilterHasPrefix(showSuggestions, word, true)
+		}
+	case Use:
+		return prompt.FilterHasPrefix(c.databaseSuggestions(), word, true)
+	case Rp:
+		return prompt.FilterHasPrefix(c.retentionPolicySuggestions(), word, true)
+	}
+	return []prompt.Suggest{}
+}
+
+func (c *Completer) databaseSuggestions() []prompt.Suggest {
+	names := c.databases.get(c.opts.TTL, func() []string {
+		ctx, cancel := context.WithTimeout(context.Background(), completionLookupTimeout)
+		defer cancel()
+		body, err := c.opts.Querier(ctx, &QueryValue{Command: "show databases"})
+		if err != nil {
+			return nil
+		}
+		return parseNameColumn(body)
+	})
+	return nameSuggestions(names)
+}
+
+// retentionPolicySuggestions scopes the lookup to the currently selected
+// database: openGemini rejects a bare "show retention policies" with no
+// database in scope, so this falls through to the "db" query parameter
+// instead of an "ON <db>" clause.
+func (c *Completer) retentionPolicySuggestions() []prompt.Suggest {
+	names := c.retention.get(c.opts.TTL, func() []string {
+		ctx, cancel := context.WithTimeout(context.Background(), completionLookupTimeout)
+		defer cancel()
+		body, err := c.opts.Querier(ctx, &QueryValue{Command: "show retention policies", Database: c.opts.Database()})
+		if err != nil {
+			return nil
+		}
+		return parseNameColumn(body)
+	})
+	return nameSuggestions(names)
+}
+
+// parseNameColumn pulls the first column of every row out of a QueryResult,
+// which is where openGemini puts the "name" for show databases/retention
+// policies/measurements style queries.
+func parseNameColumn(body []byte) []string {
+	var qr QueryResult
+	if err := json.Unmarshal(body, &qr); err != nil {
+		return nil
+	}
+	var names []string
+	for _, result := range qr.Results {
+		for _, series := range result.Series {
+			for _, value := range series.Values {
+				if len(value) == 0 {
+					continue
+				}
+				if name, ok := value[0].(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func nameSuggestions(names []string) []prompt.Suggest {
+	suggestions := make([]prompt.Suggest, len(names))
+	for i, name := range names {
+		suggestions[i] = prompt.Suggest{Text: name}
+	}
+	return suggestions
+}
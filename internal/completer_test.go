@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompleterRetentionPolicySuggestionsScopesToDatabase(t *testing.T) {
+	var gotDB string
+	c := NewCompleterWithOptions(CompleterOptions{
+		Querier: func(ctx context.Context, q *QueryValue) ([]byte, error) {
+			gotDB = q.Database
+			return []byte(`{"results":[{"series":[{"columns":["name","duration"],"values":[["autogen","0s"]]}]}]}`), nil
+		},
+		Database: func() string { return "mydb" },
+	})
+
+	suggestions := c.retentionPolicySuggestions()
+
+	if gotDB != "mydb" {
+		t.Fatalf("query was not scoped to the selected database: got %q, want %q", gotDB, "mydb")
+	}
+	if len(suggestions) != 1 || suggestions[0].Text != "autogen" {
+		t.Fatalf("got suggestions %v, want [autogen]", suggestions)
+	}
+}
+
+func TestCompleterRetentionPolicySuggestionsCachesResult(t *testing.T) {
+	calls := 0
+	c := NewCompleterWithOptions(CompleterOptions{
+		Querier: func(ctx context.Context, q *QueryValue) ([]byte, error) {
+			calls++
+			return []byte(`{"results":[{"series":[{"columns":["name"],"values":[["autogen"]]}]}]}`), nil
+		},
+	})
+
+	c.retentionPolicySuggestions()
+	c.retentionPolicySuggestions()
+
+	if calls != 1 {
+		t.Fatalf("expected the second call to hit the cache, got %d querier calls", calls)
+	}
+}
+
+func TestCompleterDatabaseSuggestionsSwallowsQueryError(t *testing.T) {
+	c := NewCompleterWithOptions(CompleterOptions{
+		Querier: func(ctx context.Context, q *QueryValue) ([]byte, error) {
+			return nil, errors.New("boom")
+		},
+	})
+
+	if got := c.databaseSuggestions(); len(got) != 0 {
+		t.Fatalf("got %v, want no suggestions when the query errors", got)
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Format renders a QueryResult to w. resolveFormat returns a fresh value for
+// every query, so an implementation may accumulate state across a single
+// query's WriteChunk calls (e.g. to emit a header only once), but must not
+// assume that state persists across separate queries.
+type Format interface {
+	Write(w io.Writer, qr *QueryResult) error
+	// WriteChunk renders a single chunk of a streamed, chunked query response.
+	// It is called once per chunk as they arrive, so output appears
+	// incrementally instead of after the whole result set has been received.
+	WriteChunk(w io.Writer, qr *QueryResult) error
+}
+
+// resolveFormat maps a "format"/-format name to its Format, defaulting to
+// ColumnFormat when name is empty.
+func resolveFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "column":
+		return &ColumnFormat{}, nil
+	case "csv":
+		return &CSVFormat{}, nil
+	case "json":
+		return &JSONFormat{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", name)
+	}
+}
+
+// ColumnFormat renders series as aligned tables, one per series, matching the
+// client's original interactive output.
+type ColumnFormat struct{}
+
+func (f *ColumnFormat) Write(w io.Writer, qr *QueryResult) error {
+	for _, result := range qr.Results {
+		for _, series := range result.Series {
+			var tags []string
+			for k, v := range series.Tags {
+				tags = append(tags, fmt.Sprintf("%s=%s", k, v))
+			}
+			sort.Strings(tags)
+
+			if series.Name != "" {
+				_, _ = fmt.Fprintf(w, "name: %s\n", series.Name)
+			}
+			if len(tags) != 0 {
+				_, _ = fmt.Fprintf(w, "tags: %s\n", strings.Join(tags, ", "))
+			}
+
+			table := tablewriter.NewWriter(w)
+			table.SetAutoFormatHeaders(false)
+			table.SetHeader(series.Columns)
+			for _, value := range series.Values {
+				tuple := make([]string, len(value))
+				for i, val := range value {
+					tuple[i] = fmt.Sprintf("%v", val)
+				}
+				table.Append(tuple)
+			}
+			table.Render()
+			_, _ = fmt.Fprintf(w, "%d columns, %d rows in set\n\n", len(series.Columns), len(series.Values))
+		}
+	}
+	return nil
+}
+
+func (f *ColumnFormat) WriteChunk(w io.Writer, qr *QueryResult) error {
+	return f.Write(w, qr)
+}
+
+// CSVFormat writes each series as a header row of series.Columns followed by
+// its value rows. WriteChunk remembers which series it has already written a
+// header for, so a chunked query's header appears once per series instead of
+// once per chunk.
+type CSVFormat struct {
+	headerWritten map[string]bool
+}
+
+func (f *CSVFormat) Write(w io.Writer, qr *QueryResult) error {
+	cw := csv.NewWriter(w)
+	for _, result := range qr.Results {
+		for _, series := range result.Series {
+			if err := cw.Write(series.Columns); err != nil {
+				return err
+			}
+			for _, value := range series.Values {
+				row := make([]string, len(value))
+				for i, val := range value {
+					row[i] = fmt.Sprintf("%v", val)
+				}
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (f *CSVFormat) WriteChunk(w io.Writer, qr *QueryResult) error {
+	cw := csv.NewWriter(w)
+	for _, result := range qr.Results {
+		for _, series := range result.Series {
+			key := seriesKey(series)
+			if !f.headerWritten[key] {
+				if err := cw.Write(series.Columns); err != nil {
+					return err
+				}
+				if f.headerWritten == nil {
+					f.headerWritten = make(map[string]bool)
+				}
+				f.headerWritten[key] = true
+			}
+			for _, value := range series.Values {
+				row := make([]string, len(value))
+				for i, val := range value {
+					row[i] = fmt.Sprintf("%v", val)
+				}
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// seriesKey identifies a series by name and tag set, so CSVFormat.WriteChunk
+// can tell whether two chunks belong to the same series.
+func seriesKey(s *Series) string {
+	var b strings.Builder
+	b.WriteString(s.Name)
+	keys := make([]string, 0, len(s.Tags))
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(s.Tags[k])
+	}
+	return b.String()
+}
+
+// JSONFormat emits the raw QueryResult as-is.
+type JSONFormat struct{}
+
+func (f *JSONFormat) Write(w io.Writer, qr *QueryResult) error {
+	return json.NewEncoder(w).Encode(qr)
+}
+
+func (f *JSONFormat) WriteChunk(w io.Writer, qr *QueryResult) error {
+	return f.Write(w, qr)
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCSVFormatWriteChunkEmitsHeaderOnce(t *testing.T) {
+	f := &CSVFormat{}
+	var buf bytes.Buffer
+
+	chunk := func(rows ...SeriesValue) *QueryResult {
+		return &QueryResult{Results: []*SeriesResult{{
+			Series: []*Series{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  rows,
+			}},
+		}}}
+	}
+
+	if err := f.WriteChunk(&buf, chunk(SeriesValue{1, 2})); err != nil {
+		t.Fatalf("WriteChunk #1: %v", err)
+	}
+	if err := f.WriteChunk(&buf, chunk(SeriesValue{3, 4})); err != nil {
+		t.Fatalf("WriteChunk #2: %v", err)
+	}
+
+	out := buf.String()
+	if got := strings.Count(out, "time,value"); got != 1 {
+		t.Fatalf("header appeared %d times across chunks, want 1:\n%s", got, out)
+	}
+}
+
+func TestCSVFormatWriteChunkTracksHeadersPerSeries(t *testing.T) {
+	f := &CSVFormat{}
+	var buf bytes.Buffer
+
+	cpu := &QueryResult{Results: []*SeriesResult{{
+		Series: []*Series{{Name: "cpu", Columns: []string{"time", "value"}, Values: []SeriesValue{{1, 2}}}},
+	}}}
+	mem := &QueryResult{Results: []*SeriesResult{{
+		Series: []*Series{{Name: "mem", Columns: []string{"time", "value"}, Values: []SeriesValue{{3, 4}}}},
+	}}}
+
+	if err := f.WriteChunk(&buf, cpu); err != nil {
+		t.Fatalf("WriteChunk cpu: %v", err)
+	}
+	if err := f.WriteChunk(&buf, mem); err != nil {
+		t.Fatalf("WriteChunk mem: %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "time,value"); got != 2 {
+		t.Fatalf("header appeared %d times across distinct series, want 2 (one per series):\n%s", got, buf.String())
+	}
+}
@@ -1,21 +1,73 @@
 package internal
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// gzipThreshold is the line-protocol body size above which write()
+// gzip-compresses the request.
+const gzipThreshold = 1024
+
 type HttpClient struct {
-	Host       string
-	Port       int
-	BasicToken string
-	c          *http.Client
+	Host            string
+	Port            int
+	Scheme          string
+	BasicToken      string
+	TLSConfig       *tls.Config
+	MaxRetries      int
+	RetryWaitMin    time.Duration
+	RetryWaitMax    time.Duration
+	RetryableStatus func(status int) bool
+	c               *http.Client
+	transport       *http.Transport
+}
+
+// QueryError is returned when openGemini responds with a 4xx or 5xx status. It
+// carries the status code so callers can distinguish, say, an auth failure
+// from a server-side timeout without string-matching the message.
+type QueryError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.StatusCode, e.Message)
+}
+
+func parseQueryError(statusCode int, body []byte) error {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		message = payload.Error
+	}
+	return &QueryError{StatusCode: statusCode, Message: message}
+}
+
+func defaultRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
 }
 
 func (receiver *HttpClient) generateBasicToken(u, p string) {
@@ -26,19 +78,127 @@ func (receiver *HttpClient) generateBasicToken(u, p string) {
 
 var stdHttpClient *HttpClient
 
-func NewHttpClient(host string, port int) {
-	stdHttpClient = &HttpClient{
-		Host: host,
-		Port: port,
-		c: &http.Client{
+// HttpClientOption configures TLS/SSL behaviour on an HttpClient at construction time.
+type HttpClientOption func(*HttpClient) error
+
+// WithSSL toggles the https scheme, initializing an empty TLSConfig if necessary.
+func WithSSL(enabled bool) HttpClientOption {
+	return func(receiver *HttpClient) error {
+		receiver.setSSL(enabled)
+		return nil
+	}
+}
+
+// WithUnsafeSSL enables https while skipping server certificate verification.
+func WithUnsafeSSL(enabled bool) HttpClientOption {
+	return func(receiver *HttpClient) error {
+		receiver.setUnsafeSSL(enabled)
+		return nil
+	}
+}
+
+// WithCACert loads a PEM encoded CA bundle used to verify the server certificate.
+func WithCACert(path string) HttpClientOption {
+	return func(receiver *HttpClient) error {
+		return receiver.setCACert(path)
+	}
+}
+
+// WithClientCert loads an mTLS client keypair presented to the server.
+func WithClientCert(certFile, keyFile string) HttpClientOption {
+	return func(receiver *HttpClient) error {
+		return receiver.setClientCert(certFile, keyFile)
+	}
+}
+
+func (receiver *HttpClient) tlsConfig() *tls.Config {
+	if receiver.TLSConfig == nil {
+		receiver.TLSConfig = &tls.Config{}
+	}
+	return receiver.TLSConfig
+}
+
+func (receiver *HttpClient) setSSL(enabled bool) {
+	if enabled {
+		receiver.Scheme = "https"
+		receiver.tlsConfig()
+	} else {
+		receiver.Scheme = "http"
+	}
+	receiver.syncTransport()
+}
+
+func (receiver *HttpClient) setUnsafeSSL(enabled bool) {
+	if enabled {
+		receiver.Scheme = "https"
+	}
+	receiver.tlsConfig().InsecureSkipVerify = enabled
+	receiver.syncTransport()
+}
+
+func (receiver *HttpClient) setCACert(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read cacert: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("cacert %s: no certificates found", path)
+	}
+	receiver.Scheme = "https"
+	receiver.tlsConfig().RootCAs = pool
+	receiver.syncTransport()
+	return nil
+}
+
+func (receiver *HttpClient) setClientCert(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("load client cert: %w", err)
+	}
+	receiver.Scheme = "https"
+	cfg := receiver.tlsConfig()
+	cfg.Certificates = append(cfg.Certificates, cert)
+	receiver.syncTransport()
+	return nil
+}
+
+// syncTransport pushes the current TLSConfig into the live transport so toggling
+// TLS options from the REPL takes effect without reconstructing the client.
+func (receiver *HttpClient) syncTransport() {
+	if receiver.transport != nil {
+		receiver.transport.TLSClientConfig = receiver.TLSConfig
+	}
+}
+
+func NewHttpClient(host string, port int, opts ...HttpClientOption) error {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
 			Timeout: time.Second * 5,
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout: time.Second * 5,
-				}).DialContext,
-			},
+		}).DialContext,
+	}
+	client := &HttpClient{
+		Host:            host,
+		Port:            port,
+		Scheme:          "http",
+		MaxRetries:      3,
+		RetryWaitMin:    time.Millisecond * 100,
+		RetryWaitMax:    time.Second * 5,
+		RetryableStatus: defaultRetryableStatus,
+		transport:       transport,
+		c: &http.Client{
+			Timeout:   time.Second * 5,
+			Transport: transport,
 		},
 	}
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return err
+		}
+	}
+	transport.TLSClientConfig = client.TLSConfig
+	stdHttpClient = client
+	return nil
 }
 
 type QueryValue struct {
@@ -46,47 +206,228 @@ type QueryValue struct {
 	RetentionPolicy string
 	Command         string
 	Precision       string
+	Chunked         bool
+	ChunkSize       int
 }
 
-func (receiver *HttpClient) query(q *QueryValue) ([]byte, error) {
+func (receiver *HttpClient) query(ctx context.Context, q *QueryValue) ([]byte, error) {
+	return receiver.innerRequest(ctx, receiver.queryURL(), strings.NewReader(q.encode().Encode()), nil, receiver.MaxRetries, true)
+}
+
+// queryOnce issues a single, non-retrying query with no backoff between
+// attempts. It exists for callers like the completer that run on the
+// interactive input loop: they must stay bounded by the caller's own context
+// deadline rather than stdHttpClient's full retry-and-backoff budget.
+func (receiver *HttpClient) queryOnce(ctx context.Context, q *QueryValue) ([]byte, error) {
+	return receiver.innerRequest(ctx, receiver.queryURL(), strings.NewReader(q.encode().Encode()), nil, 0, false)
+}
+
+func (q *QueryValue) encode() url.Values {
 	var qv = make(url.Values)
 	qv.Add("db", q.Database)
 	qv.Add("rp", q.RetentionPolicy)
 	qv.Add("q", q.Command)
 	qv.Add("epoch", q.Precision)
-	var u = fmt.Sprintf("http://%s:%d/query", receiver.Host, receiver.Port)
-	return receiver.innerRequest(u, strings.NewReader(qv.Encode()))
+	if q.Chunked {
+		qv.Add("chunked", "true")
+		if q.ChunkSize > 0 {
+			qv.Add("chunk_size", strconv.Itoa(q.ChunkSize))
+		}
+	}
+	return qv
 }
 
-type WriteValue struct {
-	Database        string
-	RetentionPolicy string
-	LineProtocol    io.Reader
+func (receiver *HttpClient) queryURL() string {
+	return fmt.Sprintf("%s://%s:%d/query", receiver.Scheme, receiver.Host, receiver.Port)
 }
 
-func (receiver *HttpClient) write(w *WriteValue) error {
-	var u = fmt.Sprintf("http://%s:%d/write?db=%s&rp=%s", receiver.Host, receiver.Port, w.Database, w.RetentionPolicy)
-	_, err := receiver.innerRequest(u, w.LineProtocol)
-	return err
-}
+// ChunkHandler processes one decoded chunk of a streamed query response.
+type ChunkHandler func(*QueryResult) error
 
-func (receiver *HttpClient) innerRequest(u string, reader io.Reader) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodPost, u, reader)
+// queryChunked streams the /query response instead of buffering it, decoding
+// newline-delimited QueryResult objects as openGemini emits them and handing
+// each to handle as soon as it arrives. Unlike innerRequest it does not retry:
+// replaying a multi-gigabyte result set to recover from a transient error
+// costs more than it's worth, so callers get a single attempt.
+func (receiver *HttpClient) queryChunked(ctx context.Context, q *QueryValue, handle ChunkHandler) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, receiver.queryURL(), strings.NewReader(q.encode().Encode()))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept-Encoding", "gzip")
 	if receiver.BasicToken != "" {
 		req.Header.Set("Authorization", "Basic "+receiver.BasicToken)
 	}
+
 	resp, err := receiver.c.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
-	r, err := io.ReadAll(resp.Body)
+
+	body, err := decompress(resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		raw, _ := io.ReadAll(body)
+		return parseQueryError(resp.StatusCode, raw)
+	}
+
+	dec := json.NewDecoder(body)
+	for dec.More() {
+		var qr QueryResult
+		if err := dec.Decode(&qr); err != nil {
+			return err
+		}
+		if err := handle(&qr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type WriteValue struct {
+	Database        string
+	RetentionPolicy string
+	LineProtocol    io.Reader
+}
+
+func (receiver *HttpClient) write(ctx context.Context, w *WriteValue) error {
+	var u = fmt.Sprintf("%s://%s:%d/write?db=%s&rp=%s", receiver.Scheme, receiver.Host, receiver.Port, w.Database, w.RetentionPolicy)
+	body, err := io.ReadAll(w.LineProtocol)
 	if err != nil {
+		return err
+	}
+	var headers map[string]string
+	if len(body) > gzipThreshold {
+		body, err = gzipCompress(body)
+		if err != nil {
+			return err
+		}
+		headers = map[string]string{"Content-Encoding": "gzip"}
+	}
+	_, err = receiver.innerRequest(ctx, u, bytes.NewReader(body), headers, receiver.MaxRetries, false)
+	return err
+}
+
+func gzipCompress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
 		return nil, err
 	}
-	return r, nil
+	return buf.Bytes(), nil
+}
+
+// decompress wraps resp.Body in a gzip.Reader when the server gzip-encoded
+// the response; otherwise it returns the body unchanged.
+func decompress(resp *http.Response) (io.Reader, error) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body, nil
+	}
+	return gzip.NewReader(resp.Body)
+}
+
+// innerRequest buffers the request body so it can be replayed across retries,
+// then retries retryable status codes with an exponential backoff before
+// giving up, up to maxRetries retries (maxRetries+1 attempts). ctx governs the whole call, including
+// every retry attempt, so a Ctrl-C cancellation or a "timeout" deadline aborts
+// the request immediately instead of waiting out the backoff.
+//
+// retryOnTransportError controls whether a transport-level error (err != nil
+// from c.Do, e.g. a reset connection) is itself retried. That's safe for
+// query, which is read-only, but not for write: if the POST reached the
+// server and was applied before the response was lost, retrying resubmits
+// the same line-protocol batch and duplicates points. Callers that can't
+// tolerate at-least-once semantics should pass false.
+func (receiver *HttpClient) innerRequest(ctx context.Context, u string, reader io.Reader, headers map[string]string, maxRetries int, retryOnTransportError bool) ([]byte, error) {
+	var body []byte
+	if reader != nil {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(reader); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(receiver.backoff(attempt)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept-Encoding", "gzip")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		if receiver.BasicToken != "" {
+			req.Header.Set("Authorization", "Basic "+receiver.BasicToken)
+		}
+
+		resp, err := receiver.c.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !retryOnTransportError {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		respBody, err := decompress(resp)
+		if err != nil {
+			_ = resp.Body.Close()
+			lastErr = err
+			continue
+		}
+		r, err := io.ReadAll(respBody)
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if receiver.RetryableStatus != nil && receiver.RetryableStatus(resp.StatusCode) && attempt < maxRetries {
+			lastErr = parseQueryError(resp.StatusCode, r)
+			continue
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			return r, parseQueryError(resp.StatusCode, r)
+		}
+		return r, nil
+	}
+	return nil, lastErr
+}
+
+// backoff computes wait = min(RetryWaitMax, RetryWaitMin*2^(attempt-1)) and
+// returns a random duration between wait/2 and wait, so it never exceeds
+// RetryWaitMax.
+func (receiver *HttpClient) backoff(attempt int) time.Duration {
+	min, max := receiver.RetryWaitMin, receiver.RetryWaitMax
+	if min <= 0 {
+		min = time.Millisecond * 100
+	}
+	if max <= 0 {
+		max = time.Second * 5
+	}
+	wait := min * time.Duration(int64(1)<<uint(attempt-1))
+	if wait <= 0 || wait > max {
+		wait = max
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
 }
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryableStatus(t *testing.T) {
+	cases := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusGatewayTimeout, true},
+	}
+	for _, tc := range cases {
+		if got := defaultRetryableStatus(tc.status); got != tc.want {
+			t.Errorf("defaultRetryableStatus(%d) = %v, want %v", tc.status, got, tc.want)
+		}
+	}
+}
+
+func TestHttpClientBackoffStaysWithinBounds(t *testing.T) {
+	c := &HttpClient{RetryWaitMin: 100 * time.Millisecond, RetryWaitMax: 5 * time.Second}
+	for attempt := 1; attempt <= 8; attempt++ {
+		wait := c.backoff(attempt)
+		if wait <= 0 {
+			t.Fatalf("backoff(%d) = %v, want > 0", attempt, wait)
+		}
+		if wait > c.RetryWaitMax {
+			t.Fatalf("backoff(%d) = %v, want <= RetryWaitMax %v", attempt, wait, c.RetryWaitMax)
+		}
+	}
+}
+
+func TestHttpClientBackoffDefaultsWhenUnset(t *testing.T) {
+	c := &HttpClient{}
+	wait := c.backoff(1)
+	if wait <= 0 || wait > 5*time.Second {
+		t.Fatalf("backoff(1) on zero-value client = %v, want within (0, 5s]", wait)
+	}
+}
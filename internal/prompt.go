@@ -1,17 +1,21 @@
 package internal
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/olekukonko/tablewriter"
 	"github.com/openGemini/go-prompt"
 	"golang.org/x/term"
+	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
-	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Command string
@@ -32,6 +36,8 @@ const (
 	Create    Command = "create"
 	Explain   Command = "explain"
 	Precision Command = "precision"
+	Timeout   Command = "timeout"
+	FormatCmd Command = "format"
 )
 
 type CommandLine struct {
@@ -40,13 +46,44 @@ type CommandLine struct {
 	Precision       string
 	Database        string
 	RetentionPolicy string
+	Timeout         time.Duration
+	Format          string
+	Execute         string
+	ImportFile      string
+	BatchSize       int
+	Chunked         bool
+	ChunkSize       int
 	prompt          *prompt.Prompt
+	queryMu         sync.Mutex
+	queryCancel     context.CancelFunc
+	running         bool
 }
 
+// Run starts the client. When Execute or ImportFile is set it runs that single
+// non-interactive mode and returns, bypassing prompt initialization entirely;
+// otherwise it starts the interactive REPL.
 func (cl *CommandLine) Run() {
+	if cl.Execute != "" {
+		if s := strings.TrimSpace(cl.Execute); s != "" {
+			if err := cl.runStatement(s); err != nil {
+				cl.printErr(err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+	if cl.ImportFile != "" {
+		if err := cl.importFile(cl.ImportFile); err != nil {
+			cl.printErr(err)
+			os.Exit(1)
+		}
+		return
+	}
 	defer cl.deconstruct(nil)
 	if cl.prompt == nil {
-		completer := NewCompleter()
+		completer := NewCompleterWithOptions(CompleterOptions{
+			Database: func() string { return cl.Database },
+		})
 		cl.prompt = prompt.New(
 			cl.executor,
 			completer.completer,
@@ -75,7 +112,7 @@ func (cl *CommandLine) Run() {
 				},
 				prompt.KeyBind{
 					Key: prompt.ControlC,
-					Fn:  cl.deconstruct,
+					Fn:  cl.interrupt,
 				},
 			),
 		)
@@ -83,6 +120,13 @@ func (cl *CommandLine) Run() {
 	cl.prompt.Run()
 }
 
+// executor is invoked by go-prompt's Run loop for every submitted line, on
+// the same goroutine that reads terminal input and dispatches key binds like
+// ControlC. It must therefore never block on the statement itself: doing so
+// would starve that loop and leave Ctrl-C unable to fire until the statement
+// finishes on its own. Instead it hands the statement to a goroutine and
+// returns immediately, so the loop stays free to service cl.interrupt while
+// the statement is in flight.
 func (cl *CommandLine) executor(s string) {
 	s = strings.TrimSpace(s)
 	if len(s) == 0 {
@@ -91,28 +135,72 @@ func (cl *CommandLine) executor(s string) {
 	if s == "exit" || s == "quit" || s == "\\q" {
 		cl.deconstruct(nil)
 	}
-	var err error
+	cl.queryMu.Lock()
+	if cl.running {
+		cl.queryMu.Unlock()
+		fmt.Println("a statement is already in progress; press ctrl-c to cancel it")
+		return
+	}
+	cl.running = true
+	cl.queryMu.Unlock()
+
+	go func() {
+		defer func() {
+			cl.queryMu.Lock()
+			cl.running = false
+			cl.queryMu.Unlock()
+		}()
+		if err := cl.runStatement(s); err != nil {
+			cl.printErr(err)
+		}
+	}()
+}
+
+// runStatement dispatches a single statement and returns its error instead of
+// printing it, so both the interactive executor and -execute can report it.
+func (cl *CommandLine) runStatement(s string) error {
 	switch cl.command(s) {
 	case Help:
-		err = cl.help()
+		return cl.help()
 	case Use:
-		err = cl.use(s)
+		return cl.use(s)
 	case Rp:
-		err = cl.retentionPolicy(s)
+		return cl.retentionPolicy(s)
 	case Auth:
-		err = cl.auth()
+		return cl.auth()
 	case Precision:
-		err = cl.precision(s)
+		return cl.precision(s)
+	case Timeout:
+		return cl.timeout(s)
+	case FormatCmd:
+		return cl.setFormat(s)
 	case Insert:
-		err = cl.write(s)
-	case Show, Drop, Create, Explain, Kill, Grant, Alter, Revoke, Set:
-		err = cl.query(s)
+		return cl.write(s)
+	case Set:
+		return cl.set(s)
+	case Show, Drop, Create, Explain, Kill, Grant, Alter, Revoke:
+		return cl.query(s)
 	default:
-		err = errors.New(string("unsupported command: " + cl.command(s)))
+		return errors.New("unsupported command: " + string(cl.command(s)))
 	}
-	if err != nil {
-		fmt.Println(err)
+}
+
+// printErr surfaces a QueryError's status code so auth failures and
+// server-side timeouts read differently from opaque transport errors.
+func (cl *CommandLine) printErr(err error) {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		switch qe.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			fmt.Printf("authentication failed: %s\n", qe.Message)
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			fmt.Printf("server unavailable (status %d): %s\n", qe.StatusCode, qe.Message)
+		default:
+			fmt.Println(qe)
+		}
+		return
 	}
+	fmt.Println(err)
 }
 
 func (cl *CommandLine) auth() error {
@@ -152,13 +240,194 @@ func (cl *CommandLine) precision(arg string) error {
 	return nil
 }
 
+// set dispatches the REPL's "set <key> <value>" command, letting users toggle
+// TLS and streaming behaviour without restarting the shell.
+func (cl *CommandLine) set(arg string) error {
+	parts := strings.Fields(arg)
+	if len(parts) < 3 {
+		return errors.New("invalid argument, set [ssl|unsafe-ssl|cacert|cert|chunked|chunk-size] <value>")
+	}
+	key, value := parts[1], parts[2]
+	switch key {
+	case "ssl":
+		stdHttpClient.setSSL(value == "on")
+	case "unsafe-ssl":
+		stdHttpClient.setUnsafeSSL(value == "on")
+	case "cacert":
+		return stdHttpClient.setCACert(value)
+	case "cert":
+		if len(parts) < 4 {
+			return errors.New("invalid argument, set cert <cert file> <key file>")
+		}
+		return stdHttpClient.setClientCert(value, parts[3])
+	case "chunked":
+		cl.Chunked = value == "on"
+	case "chunk-size":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid chunk-size: %w", err)
+		}
+		cl.ChunkSize = n
+	default:
+		return errors.New("unsupported set option: " + key)
+	}
+	return nil
+}
+
+func (cl *CommandLine) timeout(arg string) error {
+	parts := strings.Fields(arg)
+	if len(parts) <= 1 {
+		return errors.New("invalid argument, timeout <duration>")
+	}
+	d, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+	cl.Timeout = d
+	return nil
+}
+
+func (cl *CommandLine) setFormat(arg string) error {
+	parts := strings.Fields(arg)
+	if len(parts) <= 1 {
+		return errors.New("invalid argument, format [column|csv|json]")
+	}
+	if _, err := resolveFormat(parts[1]); err != nil {
+		return err
+	}
+	cl.Format = parts[1]
+	return nil
+}
+
+// batchSize returns BatchSize, defaulting to 5000 lines per -import batch.
+func (cl *CommandLine) batchSize() int {
+	if cl.BatchSize > 0 {
+		return cl.BatchSize
+	}
+	return 5000
+}
+
+// importFile streams a line-protocol file to stdHttpClient.write in batches,
+// reporting any batch failures and a final total.
+func (cl *CommandLine) importFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	batchSize := cl.batchSize()
+	scanner := bufio.NewScanner(f)
+	var batch []string
+	var totalLines, totalBatches, failedBatches int
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		totalBatches++
+		ctx := cl.beginQuery()
+		err := stdHttpClient.write(ctx, &WriteValue{
+			Database:        cl.Database,
+			RetentionPolicy: cl.RetentionPolicy,
+			LineProtocol:    strings.NewReader(strings.Join(batch, "\n")),
+		})
+		cl.endQuery()
+		if err != nil {
+			failedBatches++
+			fmt.Printf("batch %d failed: %v\n", totalBatches, err)
+		}
+		batch = batch[:0]
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		batch = append(batch, line)
+		totalLines++
+		if len(batch) >= batchSize {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+
+	fmt.Printf("%d lines imported in %d batches (%d failed)\n", totalLines, totalBatches, failedBatches)
+	if failedBatches > 0 {
+		return fmt.Errorf("%d of %d batches failed", failedBatches, totalBatches)
+	}
+	return nil
+}
+
+// beginQuery derives the context for the next outgoing request, applying
+// Timeout if one is set, and records its cancel func so interrupt can abort
+// the request in flight. Callers must defer endQuery.
+func (cl *CommandLine) beginQuery() context.Context {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if cl.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cl.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	cl.queryMu.Lock()
+	cl.queryCancel = cancel
+	cl.queryMu.Unlock()
+	return ctx
+}
+
+// endQuery clears the in-flight cancel func and releases it, so a
+// timeout-bounded query's context.WithTimeout timer is freed the moment the
+// request finishes instead of leaking until its original deadline.
+func (cl *CommandLine) endQuery() {
+	cl.queryMu.Lock()
+	cancel := cl.queryCancel
+	cl.queryCancel = nil
+	cl.queryMu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// interrupt handles Ctrl-C: if a query is in flight it cancels the request's
+// context and prints "^C" instead of exiting; it only falls through to
+// deconstruct when the prompt is idle and its buffer is empty.
+func (cl *CommandLine) interrupt(buf *prompt.Buffer) {
+	cl.queryMu.Lock()
+	cancel := cl.queryCancel
+	cl.queryMu.Unlock()
+	if cancel != nil {
+		cancel()
+		fmt.Println("^C")
+		return
+	}
+	if buf != nil && buf.Document().Text != "" {
+		fmt.Println("^C")
+		return
+	}
+	cl.deconstruct(buf)
+}
+
 func (cl *CommandLine) help() error {
 	fmt.Println(
 		`Usage:
 	auth                    prompts for username and password
 	use <db name>           sets current database
 	precision <format>      specifies the format of the timestamp: rfc3339, h, m, s, ms, u or ns
+	timeout <duration>      aborts a query/write that outruns the given duration, e.g. 30s
+	format <name>           sets the output format: column, csv or json
+	set ssl <on|off>        toggles https for subsequent requests
+	set unsafe-ssl <on|off> toggles https without verifying the server certificate
+	set cacert <file>       loads a PEM encoded CA bundle to verify the server certificate
+	set cert <cert> <key>   loads a client keypair for mTLS
+	set chunked <on|off>    streams large result sets in chunks instead of buffering them
+	set chunk-size <n>      sets the requested chunk size in points
 	exit/quit/ctrl+d        quits the openGemini shell
+	ctrl+c                  cancels an in-flight query; on an empty line, quits
 
 	show databases          show database names
 	show series             show series information
@@ -167,12 +436,19 @@ func (cl *CommandLine) help() error {
 	show field keys         show field key information
 
 	A full list of openGemini commands can be found at:
-	https://docs.opengemini.org`)
+	https://docs.opengemini.org
+
+	Non-interactive flags:
+	-format <name>          column (default), csv or json
+	-execute "<stmt>"       run a single statement and exit
+	-import <file>          bulk load a line protocol file and exit`)
 	return nil
 }
 
 func (cl *CommandLine) write(arg string) error {
-	return stdHttpClient.write(&WriteValue{
+	ctx := cl.beginQuery()
+	defer cl.endQuery()
+	return stdHttpClient.write(ctx, &WriteValue{
 		Database:        cl.Database,
 		RetentionPolicy: cl.RetentionPolicy,
 		LineProtocol:    strings.NewReader(arg[7:]),
@@ -180,17 +456,30 @@ func (cl *CommandLine) write(arg string) error {
 }
 
 func (cl *CommandLine) query(arg string) error {
-	b, err := stdHttpClient.query(&QueryValue{
+	ctx := cl.beginQuery()
+	defer cl.endQuery()
+	qv := &QueryValue{
 		Database:        cl.Database,
 		RetentionPolicy: cl.RetentionPolicy,
 		Command:         arg,
 		Precision:       cl.Precision,
-	})
+		Chunked:         cl.Chunked,
+		ChunkSize:       cl.ChunkSize,
+	}
+	if cl.Chunked {
+		f, err := resolveFormat(cl.Format)
+		if err != nil {
+			return err
+		}
+		return stdHttpClient.queryChunked(ctx, qv, func(qr *QueryResult) error {
+			return f.WriteChunk(os.Stdout, qr)
+		})
+	}
+	b, err := stdHttpClient.query(ctx, qv)
 	if err != nil {
 		return err
 	}
-	cl.pretty(b)
-	return nil
+	return cl.pretty(b)
 }
 
 func (cl *CommandLine) command(arg string) Command {
@@ -207,68 +496,23 @@ func (cl *CommandLine) deconstruct(_ *prompt.Buffer) {
 	os.Exit(0)
 }
 
-func (cl *CommandLine) pretty(r []byte) {
+// pretty renders a raw query response through the currently selected Format.
+func (cl *CommandLine) pretty(r []byte) error {
 	var qr = new(QueryResult)
-	err := json.Unmarshal(r, qr)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	var w = os.Stdout
-	for _, result := range qr.Results {
-		for _, series := range result.Series {
-			var tags []string
-			for k, v := range series.Tags {
-				tags = append(tags, fmt.Sprintf("%s=%s", k, v))
-				sort.Strings(tags)
-			}
-
-			if series.Name != "" {
-				_, _ = fmt.Fprintf(w, "name: %s\n", series.Name)
-			}
-			if len(tags) != 0 {
-				_, _ = fmt.Fprintf(w, "tags: %s\n", strings.Join(tags, ", "))
-			}
-
-			writer := tablewriter.NewWriter(w)
-			cl.prettyTable(series, writer)
-			writer.Render()
-			caption := fmt.Sprintf("%d columns, %d rows in set", len(series.Columns), len(series.Values))
-			fmt.Println(caption)
-			fmt.Println("")
-		}
+	if err := json.Unmarshal(r, qr); err != nil {
+		return err
 	}
-
-}
-
-func (cl *CommandLine) prettyTable(series *Series, w *tablewriter.Table) {
-	w.SetAutoFormatHeaders(false)
-	w.SetHeader(series.Columns)
-	for _, value := range series.Values {
-		tuple := make([]string, len(value))
-		for i, val := range value {
-			tuple[i] = fmt.Sprintf("%v", val)
-		}
-		w.Append(tuple)
+	f, err := resolveFormat(cl.Format)
+	if err != nil {
+		return err
 	}
+	return f.Write(os.Stdout, qr)
 }
 
 func NewCommandLine() *CommandLine {
 	return &CommandLine{}
 }
 
-type Completer struct {
-}
-
-func NewCompleter() *Completer {
-	return &Completer{}
-}
-
-func (c *Completer) completer(d prompt.Document) []prompt.Suggest {
-	return []prompt.Suggest{}
-}
-
 // SeriesResult contains the results of a series query
 type SeriesResult struct {
 	Series []*Series `json:"series,omitempty"`